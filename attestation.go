@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// maxAttestationSkew is how far a /hehojexiste request's timestamp may drift
+// from the server's clock before it's rejected as stale or replayed.
+const maxAttestationSkew = 30 * time.Second
+
+// attestationPayload returns the canonical bytes a /hehojexiste request is
+// signed over. Signing a fixed field order rather than the raw JSON body
+// means whitespace or key order in the request can't change what gets
+// verified. peeng-sign must build this string identically.
+func attestationPayload(peerID, addressMap string, timestamp int64) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d", peerID, addressMap, timestamp))
+}
+
+// verifyAttestation checks that req was signed by the private key matching
+// its (already-decoded) claimed peer ID, and that its timestamp isn't too
+// far from now. This closes the trivial spoof of POSTing any peer_id and
+// having peeng mark it active.
+func verifyAttestation(pid peer.ID, req HehojExisteRequest, now time.Time) error {
+	if req.Timestamp == 0 {
+		return fmt.Errorf("'timestamp' is required")
+	}
+	skew := now.Sub(time.Unix(req.Timestamp, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxAttestationSkew {
+		return fmt.Errorf("timestamp skew %s exceeds %s", skew, maxAttestationSkew)
+	}
+
+	pubKey, err := pid.ExtractPublicKey()
+	if err != nil {
+		return fmt.Errorf("peer_id does not self-certify a public key: %w", err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	ok, err := pubKey.Verify(attestationPayload(req.PeerID, req.AddressMap, req.Timestamp), sig)
+	if err != nil {
+		return fmt.Errorf("signature verification error: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("signature does not verify")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func signedRequest(t *testing.T, addressMap string, timestamp int64) (peer.ID, HehojExisteRequest) {
+	t.Helper()
+	priv, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateEd25519Key: %v", err)
+	}
+	pid, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		t.Fatalf("IDFromPublicKey: %v", err)
+	}
+	sig, err := priv.Sign(attestationPayload(pid.String(), addressMap, timestamp))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return pid, HehojExisteRequest{
+		PeerID:     pid.String(),
+		AddressMap: addressMap,
+		Timestamp:  timestamp,
+		Signature:  base64.StdEncoding.EncodeToString(sig),
+	}
+}
+
+func TestVerifyAttestationAcceptsValidSignature(t *testing.T) {
+	now := time.Now()
+	pid, req := signedRequest(t, "/ip4/127.0.0.1/tcp/4001", now.Unix())
+	if err := verifyAttestation(pid, req, now); err != nil {
+		t.Errorf("verifyAttestation() = %v, want nil", err)
+	}
+}
+
+func TestVerifyAttestationRejectsMissingTimestamp(t *testing.T) {
+	pid, req := signedRequest(t, "", 0)
+	req.Timestamp = 0
+	if err := verifyAttestation(pid, req, time.Now()); err == nil {
+		t.Error("verifyAttestation() = nil, want error for missing timestamp")
+	}
+}
+
+func TestVerifyAttestationRejectsStaleTimestamp(t *testing.T) {
+	now := time.Now()
+	pid, req := signedRequest(t, "", now.Add(-time.Hour).Unix())
+	if err := verifyAttestation(pid, req, now); err == nil {
+		t.Error("verifyAttestation() = nil, want error for a timestamp outside maxAttestationSkew")
+	}
+}
+
+func TestVerifyAttestationRejectsTamperedPayload(t *testing.T) {
+	now := time.Now()
+	pid, req := signedRequest(t, "/ip4/127.0.0.1/tcp/4001", now.Unix())
+	req.AddressMap = "/ip4/10.0.0.1/tcp/4001" // signed over a different address_map
+	if err := verifyAttestation(pid, req, now); err == nil {
+		t.Error("verifyAttestation() = nil, want error for a payload that doesn't match the signature")
+	}
+}
+
+func TestVerifyAttestationRejectsWrongSigner(t *testing.T) {
+	now := time.Now()
+	_, req := signedRequest(t, "", now.Unix())
+
+	_, otherPub, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateEd25519Key: %v", err)
+	}
+	otherPid, err := peer.IDFromPublicKey(otherPub)
+	if err != nil {
+		t.Fatalf("IDFromPublicKey: %v", err)
+	}
+
+	if err := verifyAttestation(otherPid, req, now); err == nil {
+		t.Error("verifyAttestation() = nil, want error when the claimed peer ID didn't produce the signature")
+	}
+}
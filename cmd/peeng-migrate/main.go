@@ -0,0 +1,102 @@
+// Command peeng-migrate copies every row from a peeng SQLite database into
+// Postgres, for operators moving from PEENG_STORE=sqlite to PEENG_STORE=postgres.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	sqlitePath := flag.String("sqlite", "./peers.db", "Path to the source SQLite database")
+	postgresDSN := flag.String("postgres", "", "Destination Postgres DSN")
+	flag.Parse()
+
+	if *postgresDSN == "" {
+		log.Fatal("-postgres DSN is required")
+	}
+
+	src, err := sql.Open("sqlite3", *sqlitePath)
+	if err != nil {
+		log.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	defer src.Close()
+
+	ctx := context.Background()
+	dst, err := pgxpool.New(ctx, *postgresDSN)
+	if err != nil {
+		log.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	defer dst.Close()
+
+	if _, err := dst.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS peers (
+			peer_id TEXT PRIMARY KEY,
+			last_time_check TIMESTAMPTZ,
+			active BOOLEAN,
+			score DOUBLE PRECISION DEFAULT 0,
+			consecutive_failures INT DEFAULT 0,
+			avg_latency_ms DOUBLE PRECISION DEFAULT 0,
+			next_check_at TIMESTAMPTZ
+		)
+	`); err != nil {
+		log.Fatalf("Failed to ensure destination table: %v", err)
+	}
+
+	rows, err := src.Query(`SELECT peer_id, last_time_check, active, score, consecutive_failures, avg_latency_ms, next_check_at FROM peers`)
+	if err != nil {
+		log.Fatalf("Failed to read from SQLite: %v", err)
+	}
+	defer rows.Close()
+
+	var copied int
+	for rows.Next() {
+		var peerID, lastTimeCheckStr string
+		var active bool
+		var score, avgLatencyMs float64
+		var consecutiveFailures int
+		var nextCheckAtStr sql.NullString
+		if err := rows.Scan(&peerID, &lastTimeCheckStr, &active, &score, &consecutiveFailures, &avgLatencyMs, &nextCheckAtStr); err != nil {
+			log.Fatalf("Failed to scan row: %v", err)
+		}
+
+		lastTimeCheck, err := time.Parse(time.RFC3339Nano, lastTimeCheckStr)
+		if err != nil {
+			log.Printf("Skipping %s: bad last_time_check %q: %v", peerID, lastTimeCheckStr, err)
+			continue
+		}
+		var nextCheckAt *time.Time
+		if nextCheckAtStr.Valid {
+			if t, err := time.Parse(time.RFC3339Nano, nextCheckAtStr.String); err == nil {
+				nextCheckAt = &t
+			}
+		}
+
+		_, err = dst.Exec(ctx, `
+			INSERT INTO peers (peer_id, last_time_check, active, score, consecutive_failures, avg_latency_ms, next_check_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (peer_id) DO UPDATE SET
+				last_time_check = excluded.last_time_check,
+				active = excluded.active,
+				score = excluded.score,
+				consecutive_failures = excluded.consecutive_failures,
+				avg_latency_ms = excluded.avg_latency_ms,
+				next_check_at = excluded.next_check_at
+		`, peerID, lastTimeCheck, active, score, consecutiveFailures, avgLatencyMs, nextCheckAt)
+		if err != nil {
+			log.Fatalf("Failed to write %s to Postgres: %v", peerID, err)
+		}
+		copied++
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Error reading SQLite rows: %v", err)
+	}
+
+	log.Printf("Copied %d peer row(s) from %s to Postgres.", copied, *sqlitePath)
+}
@@ -0,0 +1,73 @@
+// Command peeng-sign produces a signed /hehojexiste request body, for
+// testing peeng's peer-attestation check without standing up a full libp2p
+// node. It generates a fresh keypair on every run, prints the peer ID it
+// derives to stderr and writes the request body to stdout.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func main() {
+	keyType := flag.String("type", "ed25519", "key type to generate: ed25519 or secp256k1")
+	addressMap := flag.String("address", "", "address_map to include in the request")
+	flag.Parse()
+
+	priv, pub, err := generateKey(*keyType)
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+
+	pid, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		log.Fatalf("Failed to derive peer ID: %v", err)
+	}
+
+	timestamp := time.Now().Unix()
+	sig, err := priv.Sign(attestationPayload(pid.String(), *addressMap, timestamp))
+	if err != nil {
+		log.Fatalf("Failed to sign request: %v", err)
+	}
+
+	body := map[string]any{
+		"peer_id":     pid.String(),
+		"address_map": *addressMap,
+		"timestamp":   timestamp,
+		"signature":   base64.StdEncoding.EncodeToString(sig),
+	}
+
+	fmt.Fprintf(os.Stderr, "Signed as peer %s\n", pid.String())
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(body); err != nil {
+		log.Fatalf("Failed to encode request body: %v", err)
+	}
+}
+
+func generateKey(keyType string) (crypto.PrivKey, crypto.PubKey, error) {
+	switch keyType {
+	case "ed25519":
+		return crypto.GenerateEd25519Key(rand.Reader)
+	case "secp256k1":
+		return crypto.GenerateSecp256k1Key(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
+
+// attestationPayload mirrors the canonical signed format in the server's
+// attestation.go; duplicated here since this binary doesn't import peeng's
+// package main.
+func attestationPayload(peerID, addressMap string, timestamp int64) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d", peerID, addressMap, timestamp))
+}
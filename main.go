@@ -3,7 +3,6 @@ package main
 import (
 	"bufio"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,118 +13,110 @@ import (
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
 )
 
-const INACTIVE_QUERY = `SELECT peer_id FROM peers WHERE active = 0  and last_time_check < datetime('now', '-5 minutes') ORDER BY last_time_check ASC LIMIT 10`
-const OLD_QUERY = `SELECT peer_id FROM peers WHERE last_time_check < datetime('now', '-30 minutes') ORDER BY last_time_check ASC LIMIT 5`
+const dueBatchSize = 50
 
 // Peer represents a peer entry in the database
 type Peer struct {
-	PeerID        string    `json:"peer_id"`
-	LastTimeCheck time.Time `json:"last_time_check"`
-	Active        bool      `json:"active"`
+	PeerID              string    `json:"peer_id"`
+	LastTimeCheck       time.Time `json:"last_time_check"`
+	Active              bool      `json:"active"`
+	Score               float64   `json:"score"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	AvgLatencyMs        float64   `json:"avg_latency_ms"`
+	NextCheckAt         time.Time `json:"next_check_at"`
 }
 
-// HehojExisteRequest represents the request body for /hehojexiste
+// HehojExisteRequest represents the request body for /hehojexiste. Signature
+// and Timestamp let handleHehojExiste verify the claim in attestation.go
+// instead of trusting any caller-supplied peer_id at face value.
 type HehojExisteRequest struct {
 	PeerID     string `json:"peer_id"`
 	AddressMap string `json:"address_map"`
+	Signature  string `json:"signature"`
+	Timestamp  int64  `json:"timestamp"`
 }
 
-var (
-	db      *sql.DB
-	dbMu    sync.Mutex // Mutex for database operations
-	ipfsAPI string
-)
+var ipfsAPI string
 
 func main() {
+	ctx := context.Background()
 	var err error
-	db, err = sql.Open("sqlite3", "./peers.db")
+	store, err = newStoreFromEnv(ctx)
 	if err != nil {
-		logFatal("Failed to open database", err)
+		logFatal("Failed to initialize store", err)
 	}
-	defer db.Close()
+	defer store.Close()
 
 	ipfsAPI = os.Getenv("IPFS_API")
 	if ipfsAPI == "" {
 		ipfsAPI = "http://127.0.0.1:5001"
 	}
 
-	createTable()
+	prober = newProber()
 	go workerLoop()
 
+	refreshActivePeersGauge()
+
+	for _, url := range strings.Split(os.Getenv("PEENG_REPLICATE_PEERS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			go startReplicationClient(url)
+		}
+	}
+
 	http.HandleFunc("/peers", handlePeers)
 	http.HandleFunc("/hehojexiste", handleHehojExiste)
+	http.HandleFunc("/metrics", handleMetrics)
+	http.HandleFunc("/replicate", handleReplicate)
 	http.HandleFunc("/", handleHealth)
 	log.Println("\x1b[1;32m[INFO]\x1b[0m API listening on :8080 …")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
-func createTable() {
-	sqlStmt := `CREATE TABLE IF NOT EXISTS peers (
-		peer_id TEXT PRIMARY KEY,
-		last_time_check TIMESTAMP,
-		active BOOLEAN
-	);`
-	if _, err := db.Exec(sqlStmt); err != nil {
-		logFatal("Failed to create table", err)
-	}
-	log.Println("\x1b[1;34m[INFO]\x1b[0m Database table 'peers' ensured.")
-}
-
 func workerLoop() {
 	for {
-		log.Println("\x1b[3;33m[WORKER]\x1b[0m Skipping public DHT …")
-		log.Println("\x1b[3;33m[WORKER]\x1b[0m Inactive peers loop started")
-		pingPeersLoop(INACTIVE_QUERY, 1*time.Second)
-		log.Println("\x1b[3;33m[WORKER]\x1b[0m Inactive peers loop finished")
-		pingPeersLoop(OLD_QUERY, 1*time.Second)
-	}
-}
-
-// pingPeersLoop pings a list of peers from a query and updates their status
-func pingPeersLoop(query string, sleep time.Duration) {
-	for _, pid := range getPeerIDs(query) {
-		ok := pingPeer(pid)
-		log.Printf("\x1b[3;33m[WORKER]\x1b[0m Pinged %s, result: %t", pid, ok)
-		upsertPeer(pid, time.Now(), ok)
-		time.Sleep(sleep)
+		start := time.Now()
+		dispatchDuePeers()
+		workerLoopDurationSeconds.Set(time.Since(start).Seconds())
+		time.Sleep(1 * time.Second)
 	}
 }
 
-// getPeerIDs returns a slice of peer IDs from a query
-func getPeerIDs(query string) []string {
-	dbMu.Lock()
-	rows, err := db.Query(query)
-	dbMu.Unlock()
+// dispatchDuePeers pings every peer currently due for a check across a
+// bounded worker pool, instead of serially with a fixed sleep between peers.
+func dispatchDuePeers() {
+	pids, err := store.GetDuePeers(context.Background(), dueBatchSize)
 	if err != nil {
-		logError("Failed to query peers for ping", err)
-		return nil
+		logError("Failed to query due peers", err)
+		return
 	}
-	defer rows.Close()
-	var pids []string
-	for rows.Next() {
-		log.Printf("Debug: Scanning peer ID")
-		var pid string
-		if err := rows.Scan(&pid); err != nil {
-			logError("Failed to scan peer ID", err)
-			continue
-		}
-		pids = append(pids, pid)
+	if len(pids) == 0 {
+		return
 	}
-	return pids
-}
+	log.Printf("\x1b[3;33m[WORKER]\x1b[0m Dispatching %d due peer(s)", len(pids))
 
-// Removed unused fetchSwarmPeers for clarity
-
-// pingPeer pings a peer by ID
-func pingPeer(peerID string) bool {
-	return pingPeerWithAddress(peerID, "")
+	sem := make(chan struct{}, probeConcurrency)
+	var wg sync.WaitGroup
+	for _, pid := range pids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pid string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ok, latencyMs := prober.Ping(pid, "")
+			log.Printf("\x1b[3;33m[WORKER]\x1b[0m Pinged %s, result: %t", pid, ok)
+			recordPingResult(pid, time.Now(), ok, latencyMs)
+		}(pid)
+	}
+	wg.Wait()
 }
 
-// pingPeerWithAddress pings a peer with an optional address
-func pingPeerWithAddress(peerID, addressMap string) bool {
+// pingPeerWithAddress pings a peer with an optional address, returning
+// whether it was reachable and the average pong latency in milliseconds.
+func pingPeerWithAddress(peerID, addressMap string) (bool, float64) {
 	const count = 4
 	const timeout = 30 * time.Second
 	log.Printf("\x1b[1;35m[PING]\x1b[0m Attempting to ping: %s (Address: %s)", peerID, addressMap)
@@ -141,29 +132,29 @@ func pingPeerWithAddress(peerID, addressMap string) bool {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		logError("Failed to create HTTP request", err)
-		return false
+		return false, 0
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		logError("HTTP request failed", err)
-		return false
+		return false, 0
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		logError(fmt.Sprintf("Ping returned HTTP status %d", resp.StatusCode), nil)
-		return false
+		return false, 0
 	}
 
 	pongCount, totalLatency := parsePingResponse(resp.Body, peerID)
 	if pongCount > 0 {
 		avgLatencyMs := float64(totalLatency) / float64(pongCount) / 1e6
 		log.Printf("\x1b[1;32m[PING]\x1b[0m %s average latency: %.2f ms (%d/%d pongs)", peerID, avgLatencyMs, pongCount, count)
-		return true
+		return true, avgLatencyMs
 	}
 	logError(fmt.Sprintf("No pongs received from %s", peerID), nil)
-	return false
+	return false, 0
 }
 
 // parsePingResponse parses the ping response and returns pong count and total latency
@@ -197,50 +188,13 @@ func parsePingResponse(body io.Reader, peerID string) (pongCount int, totalLaten
 	return
 }
 
-// upsertPeer inserts or updates a peer in the database
-func upsertPeer(peerID string, checkTime time.Time, active bool) {
-	dbMu.Lock()
-	defer dbMu.Unlock()
-	_, err := db.Exec(`
-		INSERT INTO peers (peer_id, last_time_check, active)
-		VALUES (?, ?, ?)
-		ON CONFLICT(peer_id) DO UPDATE SET
-			last_time_check = excluded.last_time_check,
-			active = excluded.active
-	`, peerID, checkTime, active)
-	if err != nil {
-		logError(fmt.Sprintf("DB upsert error for %s", peerID), err)
-	} else {
-		log.Printf("\x1b[1;36m[DB]\x1b[0m Upserted peer %s (Active: %t).", peerID, active)
-	}
-}
-
 // handlePeers serves the /peers endpoint
 func handlePeers(w http.ResponseWriter, r *http.Request) {
-	dbMu.Lock()
-	rows, err := db.Query("SELECT peer_id, last_time_check, active FROM peers ORDER BY last_time_check DESC")
-	dbMu.Unlock()
+	peers, err := store.ListPeers(r.Context())
 	if err != nil {
 		http.Error(w, fmt.Sprintf("\x1b[1;31m[ERROR]\x1b[0m Failed to query peers from DB: %v", err), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var peers []Peer
-	for rows.Next() {
-		var p Peer
-		var ts string
-		if err := rows.Scan(&p.PeerID, &ts, &p.Active); err != nil {
-			logError("Failed to scan peer row", err)
-			continue
-		}
-		p.LastTimeCheck, err = time.Parse(time.RFC3339Nano, ts)
-		if err != nil {
-			logError(fmt.Sprintf("Failed to parse timestamp '%s' for peer %s", ts, p.PeerID), err)
-			p.LastTimeCheck = time.Time{}
-		}
-		peers = append(peers, p)
-	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(peers); err != nil {
@@ -264,9 +218,24 @@ func handleHehojExiste(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "\x1b[1;31m[ERROR]\x1b[0m 'peer_id' is required", http.StatusBadRequest)
 		return
 	}
+	pid, err := peer.Decode(req.PeerID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("\x1b[1;31m[ERROR]\x1b[0m Invalid 'peer_id': %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.AddressMap != "" {
+		if _, err := multiaddr.NewMultiaddr(req.AddressMap); err != nil {
+			http.Error(w, fmt.Sprintf("\x1b[1;31m[ERROR]\x1b[0m Invalid 'address_map': %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := verifyAttestation(pid, req, time.Now()); err != nil {
+		http.Error(w, fmt.Sprintf("\x1b[1;31m[ERROR]\x1b[0m Attestation failed: %v", err), http.StatusUnauthorized)
+		return
+	}
 	log.Printf("\x1b[1;3;35m[API]\x1b[0m Received /hehojexiste request for PeerID: %s, AddressMap: %s", req.PeerID, req.AddressMap)
-	pingOK := pingPeerWithAddress(req.PeerID, req.AddressMap)
-	upsertPeer(req.PeerID, time.Now(), pingOK)
+	pingOK, latencyMs := prober.Ping(req.PeerID, req.AddressMap)
+	recordPingResult(req.PeerID, time.Now(), pingOK, latencyMs)
 	response := map[string]bool{"ping_successful": pingOK}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus collectors for peeng. These are derived from the ping results
+// every Prober reports through recordPingResult, the peers table and
+// workerLoop's timing, so operators can scrape peeng into Grafana instead of
+// grepping logs.
+//
+// No collector is labeled by peer_id: with a few thousand peers (the
+// scale peeng targets) a per-peer label would mean a few thousand distinct
+// series per histogram bucket, which is exactly the kind of cardinality
+// explosion Prometheus operators are told to avoid. "success" is bounded to
+// two values, so it stays cheap.
+var (
+	pingLatencySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "peeng_ping_latency_seconds",
+		Help:    "Observed ping latency across all peers.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5},
+	})
+
+	pongTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "peeng_pong_total",
+		Help: "Pong responses received, labeled by success.",
+	}, []string{"success"})
+
+	activePeersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "peeng_active_peers",
+		Help: "Number of peers currently marked active in the database.",
+	})
+
+	dbUpsertsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "peeng_db_upserts_total",
+		Help: "Total number of peer rows upserted.",
+	})
+
+	workerLoopDurationSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "peeng_worker_loop_duration_seconds",
+		Help: "Duration of the most recently completed workerLoop iteration.",
+	})
+)
+
+// handleMetrics serves Prometheus metrics at /metrics.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	promhttp.Handler().ServeHTTP(w, r)
+}
+
+// refreshActivePeersGauge recomputes peeng_active_peers from the store with a
+// full count. Only cheap at startup - per-ping updates go through
+// recordActiveTransition instead so a busy worker pool isn't doing a table
+// scan on every probe.
+func refreshActivePeersGauge() {
+	count, err := store.CountActive(context.Background())
+	if err != nil {
+		logError("Failed to refresh active peers gauge", err)
+		return
+	}
+	activePeersGauge.Set(float64(count))
+}
+
+// recordActiveTransition adjusts peeng_active_peers by the net change from a
+// single peer's active flag flipping, so a Store's Upsert/MergeReplicated can
+// keep the gauge correct without recounting the whole table.
+func recordActiveTransition(wasActive, isActive bool) {
+	switch {
+	case isActive && !wasActive:
+		activePeersGauge.Inc()
+	case !isActive && wasActive:
+		activePeersGauge.Dec()
+	}
+}
+
+// recordPingOutcome records pingLatencySeconds/pongTotal for a single Ping
+// call. It lives at the one call site every Prober result passes through
+// (recordPingResult) rather than inside a specific backend, so switching
+// PEENG_PROBER doesn't change what gets reported on /metrics.
+func recordPingOutcome(ok bool, latencyMs float64) {
+	if !ok {
+		pongTotal.WithLabelValues("false").Inc()
+		return
+	}
+	pingLatencySeconds.Observe(latencyMs / 1000)
+	pongTotal.WithLabelValues("true").Inc()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// histogramBucketCount returns the cumulative count of pingLatencySeconds'
+// bucket with the given le, so a test can assert an observation landed where
+// expected without depending on every other test's share of the total count.
+func histogramBucketCount(t *testing.T, le float64) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := pingLatencySeconds.Write(&m); err != nil {
+		t.Fatalf("writing pingLatencySeconds: %v", err)
+	}
+	for _, b := range m.GetHistogram().GetBucket() {
+		if b.GetUpperBound() == le {
+			return b.GetCumulativeCount()
+		}
+	}
+	t.Fatalf("pingLatencySeconds has no bucket with le=%v", le)
+	return 0
+}
+
+func TestRecordPingResultUpdatesMetrics(t *testing.T) {
+	s, err := newSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	prevStore := store
+	store = s
+	defer func() { store = prevStore }()
+
+	beforeActive := testutil.ToFloat64(activePeersGauge)
+	beforeUpserts := testutil.ToFloat64(dbUpsertsTotal)
+	beforePongOK := testutil.ToFloat64(pongTotal.WithLabelValues("true"))
+	beforePongFail := testutil.ToFloat64(pongTotal.WithLabelValues("false"))
+
+	recordPingResult("peer-metrics-a", time.Now(), true, 12.5)
+
+	if got, want := testutil.ToFloat64(activePeersGauge), beforeActive+1; got != want {
+		t.Errorf("active gauge after a new active peer = %v, want %v", got, want)
+	}
+	if got, want := testutil.ToFloat64(dbUpsertsTotal), beforeUpserts+1; got != want {
+		t.Errorf("db upserts total = %v, want %v", got, want)
+	}
+	if got, want := testutil.ToFloat64(pongTotal.WithLabelValues("true")), beforePongOK+1; got != want {
+		t.Errorf("pong total{success=true} = %v, want %v", got, want)
+	}
+
+	recordPingResult("peer-metrics-a", time.Now(), false, 0)
+
+	if got, want := testutil.ToFloat64(activePeersGauge), beforeActive; got != want {
+		t.Errorf("active gauge after the peer goes inactive = %v, want %v", got, want)
+	}
+	if got, want := testutil.ToFloat64(pongTotal.WithLabelValues("false")), beforePongFail+1; got != want {
+		t.Errorf("pong total{success=false} = %v, want %v", got, want)
+	}
+}
+
+func TestRecordPingResultObservesLatencyBucket(t *testing.T) {
+	s, err := newSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	prevStore := store
+	store = s
+	defer func() { store = prevStore }()
+
+	// 120ms falls short of the 0.5s bucket's upper bound but past 0.1s, so it
+	// should land in le="0.5" (and every wider bucket) without touching le="0.1".
+	beforeNarrow := histogramBucketCount(t, 0.1)
+	beforeWide := histogramBucketCount(t, 0.5)
+	beforePongOK := testutil.ToFloat64(pongTotal.WithLabelValues("true"))
+
+	recordPingResult("peer-metrics-bucket", time.Now(), true, 120)
+
+	if got, want := histogramBucketCount(t, 0.1), beforeNarrow; got != want {
+		t.Errorf("le=0.1 bucket count = %d, want unchanged %d", got, want)
+	}
+	if got, want := histogramBucketCount(t, 0.5), beforeWide+1; got != want {
+		t.Errorf("le=0.5 bucket count = %d, want %d", got, want)
+	}
+	if got, want := testutil.ToFloat64(pongTotal.WithLabelValues("true")), beforePongOK+1; got != want {
+		t.Errorf("pong total{success=true} = %v, want %v", got, want)
+	}
+
+	body, err := io.ReadAll(handleMetricsBody(t))
+	if err != nil {
+		t.Fatalf("reading /metrics response: %v", err)
+	}
+	if !strings.Contains(string(body), `peeng_ping_latency_seconds_bucket{le="0.5"}`) {
+		t.Errorf("/metrics body missing peeng_ping_latency_seconds_bucket{le=\"0.5\"}")
+	}
+	if !strings.Contains(string(body), `peeng_pong_total{success="true"}`) {
+		t.Errorf("/metrics body missing peeng_pong_total{success=\"true\"}")
+	}
+}
+
+// handleMetricsBody drives an actual request through handleMetrics, the same
+// handler /metrics is wired to, and returns its response body.
+func handleMetricsBody(t *testing.T) io.Reader {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Result().Body
+}
+
+func TestHandleMetricsServesPeengCollectors(t *testing.T) {
+	body, err := io.ReadAll(handleMetricsBody(t))
+	if err != nil {
+		t.Fatalf("reading /metrics response: %v", err)
+	}
+
+	for _, want := range []string{
+		"peeng_ping_latency_seconds",
+		"peeng_pong_total",
+		"peeng_active_peers",
+		"peeng_db_upserts_total",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics body missing %q", want)
+		}
+	}
+}
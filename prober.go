@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// Prober pings a single peer and reports whether it responded along with the
+// observed latency in milliseconds. This lets peeng swap how it reaches
+// peers (Kubo's HTTP API today, native libp2p as an alternative) without
+// touching the scoring/scheduling code that consumes the result.
+type Prober interface {
+	Ping(peerID, addressMap string) (ok bool, latencyMs float64)
+}
+
+var prober Prober
+
+// newProber selects a Prober implementation from PEENG_PROBER (default "kubo").
+func newProber() Prober {
+	switch strings.ToLower(os.Getenv("PEENG_PROBER")) {
+	case "libp2p":
+		p, err := newLibp2pProber()
+		if err != nil {
+			logFatal("Failed to start libp2p prober", err)
+		}
+		return p
+	default:
+		return kuboProber{}
+	}
+}
+
+// kuboProber pings peers through a colocated Kubo node's HTTP API - the
+// behavior peeng has always had.
+type kuboProber struct{}
+
+func (kuboProber) Ping(peerID, addressMap string) (bool, float64) {
+	return pingPeerWithAddress(peerID, addressMap)
+}
+
+// libp2pProber pings peers directly over libp2p, removing the hard
+// dependency on a colocated Kubo daemon.
+type libp2pProber struct {
+	host  host.Host
+	pings *ping.PingService
+}
+
+func newLibp2pProber() (*libp2pProber, error) {
+	h, err := libp2p.New()
+	if err != nil {
+		return nil, err
+	}
+	pings := ping.NewPingService(h)
+	log.Printf("\x1b[1;34m[INFO]\x1b[0m libp2p prober started with peer ID %s", h.ID())
+	return &libp2pProber{host: h, pings: pings}, nil
+}
+
+func (p *libp2pProber) Ping(peerID, addressMap string) (bool, float64) {
+	const timeout = 30 * time.Second
+
+	pid, err := peer.Decode(peerID)
+	if err != nil {
+		logError(fmt.Sprintf("Invalid peer ID %s", peerID), err)
+		return false, 0
+	}
+
+	if addressMap != "" {
+		maddr, err := multiaddr.NewMultiaddr(addressMap)
+		if err != nil {
+			logError(fmt.Sprintf("Invalid multiaddr %s for peer %s", addressMap, peerID), err)
+			return false, 0
+		}
+		addrInfo, err := peer.AddrInfoFromP2pAddr(maddr)
+		if err != nil {
+			// addressMap doesn't embed a peer ID of its own; attach it to the one we were given.
+			addrInfo = &peer.AddrInfo{ID: pid, Addrs: []multiaddr.Multiaddr{maddr}}
+		}
+		p.host.Peerstore().AddAddrs(addrInfo.ID, addrInfo.Addrs, time.Hour)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := <-p.pings.Ping(ctx, pid)
+	if result.Error != nil {
+		logError(fmt.Sprintf("libp2p ping to %s failed", peerID), result.Error)
+		return false, 0
+	}
+	latencyMs := float64(result.RTT) / 1e6
+	log.Printf("\x1b[1;32m[PING]\x1b[0m %s libp2p pong in %.2f ms", peerID, latencyMs)
+	return true, latencyMs
+}
+
+// probeConcurrency bounds how many pings dispatchDuePeers runs at once,
+// configurable via PEENG_PROBE_CONCURRENCY so a single slow peer can't stall
+// a whole cycle.
+var probeConcurrency = probeConcurrencyFromEnv()
+
+func probeConcurrencyFromEnv() int {
+	const def = 32
+	v := os.Getenv("PEENG_PROBE_CONCURRENCY")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		logError(fmt.Sprintf("Invalid PEENG_PROBE_CONCURRENCY=%q, using default %d", v, def), err)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// handleReplicate serves /replicate: a snapshot of the peers table followed
+// by one NDJSON line per subsequent Store.Upsert call, until the client disconnects.
+func handleReplicate(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "\x1b[1;31m[ERROR]\x1b[0m Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	peers, err := store.ListPeers(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("\x1b[1;31m[ERROR]\x1b[0m Failed to query peers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, p := range peers {
+		if err := enc.Encode(p); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ch, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("\x1b[3;33m[REPL]\x1b[0m Subscriber disconnected")
+			return
+		case p := <-ch:
+			if err := enc.Encode(p); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// startReplicationClient subscribes to a remote peeng's /replicate endpoint
+// and merges its observations into the local store, reconnecting with backoff.
+func startReplicationClient(url string) {
+	const initialBackoff = 1 * time.Second
+	const maxBackoff = 2 * time.Minute
+	backoff := initialBackoff
+
+	for {
+		log.Printf("\x1b[3;33m[REPL]\x1b[0m Connecting to %s", url)
+		if err := replicateFrom(url); err != nil {
+			logError(fmt.Sprintf("Replication stream from %s ended", url), err)
+		}
+		log.Printf("\x1b[3;33m[REPL]\x1b[0m Reconnecting to %s in %s", url, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// replicateFrom does a single connection attempt against a remote /replicate
+// stream, merging every peer row it receives until the stream ends.
+func replicateFrom(url string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var p Peer
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			logError("Failed to parse replicated peer line", err)
+			continue
+		}
+		applied, err := store.MergeReplicated(context.Background(), p)
+		if err != nil {
+			logError(fmt.Sprintf("Failed to merge replicated peer %s", p.PeerID), err)
+			continue
+		}
+		if !applied {
+			// p was older than what's already stored locally - not a merge.
+			continue
+		}
+		log.Printf("\x1b[1;36m[REPL]\x1b[0m Merged remote observation for %s (Active: %t)", p.PeerID, p.Active)
+		dbUpsertsTotal.Inc()
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// backoffSchedule is applied to consecutive ping failures, stepping further
+// out each time and capping at the last entry.
+var backoffSchedule = []time.Duration{
+	30 * time.Second,
+	1 * time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+}
+
+const stableLatencyThresholdMs = 200
+const stableCheckInterval = 15 * time.Second
+
+const scoreSmoothing = 0.3
+const latencySmoothing = 0.3
+
+// nextCheckInterval computes how long to wait before checking a peer again:
+// failures step through backoffSchedule by consecutive failure count, while
+// a stable, low-latency success shortens the interval instead.
+func nextCheckInterval(consecutiveFailures int, avgLatencyMs float64, ok bool) time.Duration {
+	if !ok {
+		idx := consecutiveFailures - 1
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(backoffSchedule) {
+			idx = len(backoffSchedule) - 1
+		}
+		return backoffSchedule[idx]
+	}
+	if avgLatencyMs > 0 && avgLatencyMs < stableLatencyThresholdMs {
+		return stableCheckInterval
+	}
+	return backoffSchedule[0]
+}
+
+// scoreEWMA blends the previous score with this attempt's outcome (1 for a
+// successful ping, 0 for a failure), weighting recent history more heavily.
+func scoreEWMA(prevScore float64, ok bool) float64 {
+	outcome := 0.0
+	if ok {
+		outcome = 1.0
+	}
+	return prevScore + scoreSmoothing*(outcome-prevScore)
+}
+
+// latencyEWMA blends the previous average latency with a new sample.
+func latencyEWMA(prevAvgMs, sampleMs float64) float64 {
+	if prevAvgMs == 0 {
+		return sampleMs
+	}
+	return prevAvgMs + latencySmoothing*(sampleMs-prevAvgMs)
+}
+
+// scorePing folds the outcome of a single ping attempt into a peer's prior
+// score/failure/latency history, producing the row a Store should write.
+// Pure and DB-agnostic so every Store implementation computes it the same way.
+func scorePing(peerID string, checkTime time.Time, ok bool, latencyMs float64, prevScore float64, prevFailures int, prevAvgLatency float64) Peer {
+	consecutiveFailures := prevFailures
+	if ok {
+		consecutiveFailures = 0
+	} else {
+		consecutiveFailures++
+	}
+	avgLatencyMs := prevAvgLatency
+	if ok {
+		avgLatencyMs = latencyEWMA(prevAvgLatency, latencyMs)
+	}
+	score := scoreEWMA(prevScore, ok)
+	nextCheckAt := checkTime.Add(nextCheckInterval(consecutiveFailures, avgLatencyMs, ok))
+
+	return Peer{
+		PeerID:              peerID,
+		LastTimeCheck:       checkTime,
+		Active:              ok,
+		Score:               score,
+		ConsecutiveFailures: consecutiveFailures,
+		AvgLatencyMs:        avgLatencyMs,
+		NextCheckAt:         nextCheckAt,
+	}
+}
+
+// recordPingResult persists the outcome of a single ping attempt via the
+// configured Store and updates the metrics derived from it. The Store itself
+// keeps peeng_active_peers in sync incrementally, since it's the only place
+// that knows the peer's prior active state.
+func recordPingResult(peerID string, checkTime time.Time, ok bool, latencyMs float64) {
+	p, err := store.Upsert(context.Background(), peerID, checkTime, ok, latencyMs)
+	if err != nil {
+		logError(fmt.Sprintf("DB upsert error for %s", peerID), err)
+		return
+	}
+	log.Printf("\x1b[1;36m[DB]\x1b[0m Upserted peer %s (Active: %t, Score: %.2f, NextCheck: %s).",
+		peerID, p.Active, p.Score, p.NextCheckAt.Format(time.RFC3339))
+	dbUpsertsTotal.Inc()
+	recordPingOutcome(ok, latencyMs)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextCheckIntervalBackoff(t *testing.T) {
+	cases := []struct {
+		consecutiveFailures int
+		want                int
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 1},
+		{5, 4},
+		{50, len(backoffSchedule) - 1},
+	}
+	for _, c := range cases {
+		got := nextCheckInterval(c.consecutiveFailures, 0, false)
+		want := backoffSchedule[c.want]
+		if got != want {
+			t.Errorf("nextCheckInterval(%d, _, false) = %v, want %v", c.consecutiveFailures, got, want)
+		}
+	}
+}
+
+func TestNextCheckIntervalStableSuccess(t *testing.T) {
+	got := nextCheckInterval(0, stableLatencyThresholdMs-1, true)
+	if got != stableCheckInterval {
+		t.Errorf("low-latency success interval = %v, want %v", got, stableCheckInterval)
+	}
+
+	got = nextCheckInterval(0, stableLatencyThresholdMs+1, true)
+	if got != backoffSchedule[0] {
+		t.Errorf("high-latency success interval = %v, want %v", got, backoffSchedule[0])
+	}
+}
+
+func TestScoreEWMA(t *testing.T) {
+	got := scoreEWMA(0.5, true)
+	want := 0.5 + scoreSmoothing*(1.0-0.5)
+	if got != want {
+		t.Errorf("scoreEWMA(0.5, true) = %v, want %v", got, want)
+	}
+
+	got = scoreEWMA(0.5, false)
+	want = 0.5 + scoreSmoothing*(0.0-0.5)
+	if got != want {
+		t.Errorf("scoreEWMA(0.5, false) = %v, want %v", got, want)
+	}
+}
+
+func TestLatencyEWMASeedsFromFirstSample(t *testing.T) {
+	if got := latencyEWMA(0, 42); got != 42 {
+		t.Errorf("latencyEWMA(0, 42) = %v, want 42", got)
+	}
+}
+
+func TestScorePingResetsFailuresOnSuccess(t *testing.T) {
+	p := scorePing("peer", time.Now(), true, 10, 0.2, 3, 50)
+	if p.ConsecutiveFailures != 0 {
+		t.Errorf("ConsecutiveFailures = %d, want 0 after a success", p.ConsecutiveFailures)
+	}
+	if !p.Active {
+		t.Error("Active = false, want true after a successful ping")
+	}
+}
+
+func TestScorePingIncrementsFailuresOnFailure(t *testing.T) {
+	p := scorePing("peer", time.Now(), false, 0, 0.8, 2, 50)
+	if p.ConsecutiveFailures != 3 {
+		t.Errorf("ConsecutiveFailures = %d, want 3 after another failure", p.ConsecutiveFailures)
+	}
+	if p.Active {
+		t.Error("Active = true, want false after a failed ping")
+	}
+	if p.AvgLatencyMs != 50 {
+		t.Errorf("AvgLatencyMs = %v, want unchanged 50 on failure", p.AvgLatencyMs)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Store abstracts the peers table so peeng can run against SQLite (the
+// original, single-process deployment) or Postgres (for a pool of peeng
+// instances probing concurrently against one database). The mutex-serialized
+// SQLite path becomes a bottleneck past a few thousand peers; Postgres gets
+// a real connection pool and ON CONFLICT ... DO UPDATE upserts instead.
+type Store interface {
+	// GetDuePeers returns up to limit peer IDs whose next_check_at has arrived.
+	GetDuePeers(ctx context.Context, limit int) ([]string, error)
+
+	// Upsert records the outcome of a ping attempt - updating score,
+	// consecutive_failures, avg_latency_ms and next_check_at - and returns
+	// the resulting row.
+	Upsert(ctx context.Context, peerID string, checkTime time.Time, ok bool, latencyMs float64) (Peer, error)
+
+	// ListPeers returns every known peer, most recently checked first.
+	ListPeers(ctx context.Context) ([]Peer, error)
+
+	// CountActive returns how many peers are currently marked active.
+	CountActive(ctx context.Context) (int, error)
+
+	// MergeReplicated applies a peer row received from a remote peeng's
+	// /replicate stream, preferring whichever side has the newer
+	// last_time_check. It reports whether p was newer and therefore actually
+	// written, so callers don't count or log a merge that was a no-op.
+	MergeReplicated(ctx context.Context, p Peer) (applied bool, err error)
+
+	// Subscribe registers a replication subscriber, returning a channel of
+	// upserted peers and an unsubscribe func that closes it.
+	Subscribe() (<-chan Peer, func())
+
+	Close() error
+}
+
+var store Store
+
+// newStoreFromEnv picks a Store implementation based on PEENG_STORE
+// ("sqlite" or "postgres", default "sqlite").
+func newStoreFromEnv(ctx context.Context) (Store, error) {
+	switch backend := strings.ToLower(os.Getenv("PEENG_STORE")); backend {
+	case "", "sqlite":
+		path := os.Getenv("PEENG_SQLITE_PATH")
+		if path == "" {
+			path = "./peers.db"
+		}
+		return newSQLiteStore(path)
+	case "postgres":
+		dsn := os.Getenv("PEENG_POSTGRES_DSN")
+		if dsn == "" {
+			return nil, fmt.Errorf("PEENG_POSTGRES_DSN is required when PEENG_STORE=postgres")
+		}
+		return newPostgresStore(ctx, dsn)
+	default:
+		return nil, fmt.Errorf("unknown PEENG_STORE %q", backend)
+	}
+}
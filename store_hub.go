@@ -0,0 +1,55 @@
+package main
+
+import "sync"
+
+const replSubscriberBuffer = 64
+
+// replicationHub fans out upserted peer rows to every connected /replicate
+// subscriber. Slow consumers have their oldest queued update dropped rather
+// than blocking the write that produced it. Both Store implementations hold
+// one of these in their hub field to back their Subscribe method.
+type replicationHub struct {
+	mu   sync.Mutex
+	subs map[chan Peer]struct{}
+}
+
+func newReplicationHub() *replicationHub {
+	return &replicationHub{subs: make(map[chan Peer]struct{})}
+}
+
+func (h *replicationHub) subscribe() (<-chan Peer, func()) {
+	ch := make(chan Peer, replSubscriberBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+		h.mu.Unlock()
+	}
+	return ch, unsubscribe
+}
+
+// broadcast fans a peer update out to every subscriber, drop-oldest on slow consumers.
+func (h *replicationHub) broadcast(p Peer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- p:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- p:
+			default:
+			}
+		}
+	}
+}
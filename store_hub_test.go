@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplicationHubBroadcastDropsOldestOnFullSubscriber(t *testing.T) {
+	h := newReplicationHub()
+	ch, unsubscribe := h.subscribe()
+	defer unsubscribe()
+
+	// Fill the subscriber's buffer, then push one more: the oldest queued
+	// update should be dropped rather than blocking the broadcaster.
+	for i := 0; i < replSubscriberBuffer; i++ {
+		h.broadcast(Peer{PeerID: "peer", Score: float64(i)})
+	}
+	h.broadcast(Peer{PeerID: "peer", Score: float64(replSubscriberBuffer)})
+
+	first := <-ch
+	if first.Score != 1 {
+		t.Errorf("oldest update was not dropped: got Score %v, want 1", first.Score)
+	}
+
+	var last Peer
+	for i := 0; i < replSubscriberBuffer-1; i++ {
+		last = <-ch
+	}
+	if last.Score != float64(replSubscriberBuffer) {
+		t.Errorf("last received Score = %v, want %v", last.Score, replSubscriberBuffer)
+	}
+}
+
+func TestReplicationHubUnsubscribeClosesChannel(t *testing.T) {
+	h := newReplicationHub()
+	ch, unsubscribe := h.subscribe()
+
+	unsubscribe()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected channel to be closed after unsubscribe")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for channel to close")
+	}
+
+	// Broadcasting after unsubscribe must not panic on the closed channel.
+	h.broadcast(Peer{PeerID: "peer"})
+}
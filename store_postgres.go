@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// insertPlaceholderSQLPostgres ensures a row exists for peer_id without
+// touching one that's already there, so Upsert/MergeReplicated always have a
+// row to lock with SELECT ... FOR UPDATE below - including for a peer seen
+// for the first time. last_time_check is set to '-infinity', older than any
+// real timestamp, so MergeReplicated's "only apply if newer" comparison
+// still treats a genuinely new peer's row as newer than its placeholder.
+const insertPlaceholderSQLPostgres = `
+	INSERT INTO peers (peer_id, last_time_check, active) VALUES ($1, '-infinity', false)
+	ON CONFLICT (peer_id) DO NOTHING
+`
+
+const updateSQLPostgres = `
+	UPDATE peers SET
+		last_time_check = $2,
+		active = $3,
+		score = $4,
+		consecutive_failures = $5,
+		avg_latency_ms = $6,
+		next_check_at = $7
+	WHERE peer_id = $1
+`
+
+// postgresStore backs the peers table with Postgres through a pgx
+// connection pool, so probes no longer serialize on a single mutex.
+type postgresStore struct {
+	pool *pgxpool.Pool
+	hub  *replicationHub
+}
+
+func newPostgresStore(ctx context.Context, dsn string) (*postgresStore, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	s := &postgresStore{pool: pool, hub: newReplicationHub()}
+	if err := s.migrate(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	log.Println("\x1b[1;34m[INFO]\x1b[0m Connected to Postgres; 'peers' table ensured.")
+	return s, nil
+}
+
+func (s *postgresStore) migrate(ctx context.Context) error {
+	if _, err := s.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS peers (
+			peer_id TEXT PRIMARY KEY,
+			last_time_check TIMESTAMPTZ,
+			active BOOLEAN,
+			score DOUBLE PRECISION DEFAULT 0,
+			consecutive_failures INT DEFAULT 0,
+			avg_latency_ms DOUBLE PRECISION DEFAULT 0,
+			next_check_at TIMESTAMPTZ
+		)
+	`); err != nil {
+		return err
+	}
+
+	// GetDuePeers scans next_check_at and CountActive scans active on every
+	// probe cycle; without these the concurrent worker pool serializes on
+	// full table scans as the peers table grows.
+	if _, err := s.pool.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_peers_next_check_at ON peers(next_check_at)`); err != nil {
+		return err
+	}
+	if _, err := s.pool.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_peers_active ON peers(active)`); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *postgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+func (s *postgresStore) GetDuePeers(ctx context.Context, limit int) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `SELECT peer_id FROM peers WHERE next_check_at <= now() ORDER BY next_check_at ASC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pids []string
+	for rows.Next() {
+		var pid string
+		if err := rows.Scan(&pid); err != nil {
+			return nil, err
+		}
+		pids = append(pids, pid)
+	}
+	return pids, rows.Err()
+}
+
+// Upsert reads the prior row, computes the new one, and writes it back
+// inside a single transaction with the row locked via FOR UPDATE, so a
+// concurrent Upsert/MergeReplicated for the same peer_id - the pool's whole
+// point is letting those run concurrently - can't race on prevScore/
+// prevFailures/prevAvgLatency and silently lose an update.
+func (s *postgresStore) Upsert(ctx context.Context, peerID string, checkTime time.Time, ok bool, latencyMs float64) (Peer, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Peer{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, insertPlaceholderSQLPostgres, peerID); err != nil {
+		return Peer{}, err
+	}
+
+	var prevScore, prevAvgLatency float64
+	var prevFailures int
+	var prevActive bool
+	if err := tx.QueryRow(ctx, `SELECT score, consecutive_failures, avg_latency_ms, active FROM peers WHERE peer_id = $1 FOR UPDATE`, peerID).
+		Scan(&prevScore, &prevFailures, &prevAvgLatency, &prevActive); err != nil {
+		return Peer{}, err
+	}
+
+	p := scorePing(peerID, checkTime, ok, latencyMs, prevScore, prevFailures, prevAvgLatency)
+
+	if _, err := tx.Exec(ctx, updateSQLPostgres, peerID, checkTime, p.Active, p.Score, p.ConsecutiveFailures, p.AvgLatencyMs, p.NextCheckAt); err != nil {
+		return Peer{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Peer{}, err
+	}
+
+	recordActiveTransition(prevActive, p.Active)
+	s.hub.broadcast(p)
+	return p, nil
+}
+
+// MergeReplicated applies the same lock-then-read-then-write pattern as
+// Upsert, so a replicated row landing mid-ping can't race the local worker
+// pool's own Upsert for the same peer. It reports whether p was actually
+// newer and therefore written, so replicateFrom only counts and logs merges
+// that took effect.
+func (s *postgresStore) MergeReplicated(ctx context.Context, p Peer) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, insertPlaceholderSQLPostgres, p.PeerID); err != nil {
+		return false, err
+	}
+
+	// pgx cannot scan '-infinity' into a time.Time, so the placeholder's
+	// timestamp comes back as NULL and is treated as older than p.
+	var existing *time.Time
+	var prevActive bool
+	if err := tx.QueryRow(ctx, `SELECT NULLIF(last_time_check, '-infinity'), active FROM peers WHERE peer_id = $1 FOR UPDATE`, p.PeerID).
+		Scan(&existing, &prevActive); err != nil {
+		return false, err
+	}
+	if existing != nil && !p.LastTimeCheck.After(*existing) {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, updateSQLPostgres, p.PeerID, p.LastTimeCheck, p.Active, p.Score, p.ConsecutiveFailures, p.AvgLatencyMs, p.NextCheckAt); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	recordActiveTransition(prevActive, p.Active)
+	return true, nil
+}
+
+func (s *postgresStore) ListPeers(ctx context.Context) ([]Peer, error) {
+	rows, err := s.pool.Query(ctx, `SELECT peer_id, last_time_check, active, score, consecutive_failures, avg_latency_ms, next_check_at FROM peers ORDER BY last_time_check DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var peers []Peer
+	for rows.Next() {
+		var p Peer
+		var nextCheckAt *time.Time
+		if err := rows.Scan(&p.PeerID, &p.LastTimeCheck, &p.Active, &p.Score, &p.ConsecutiveFailures, &p.AvgLatencyMs, &nextCheckAt); err != nil {
+			return nil, err
+		}
+		if nextCheckAt != nil {
+			p.NextCheckAt = *nextCheckAt
+		}
+		peers = append(peers, p)
+	}
+	return peers, rows.Err()
+}
+
+func (s *postgresStore) CountActive(ctx context.Context) (int, error) {
+	var count int
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM peers WHERE active = true`).Scan(&count)
+	return count, err
+}
+
+func (s *postgresStore) Subscribe() (<-chan Peer, func()) {
+	return s.hub.subscribe()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const dueQuerySQLite = `SELECT peer_id FROM peers WHERE next_check_at <= ? ORDER BY next_check_at ASC LIMIT ?`
+
+const upsertSQLSqlite = `
+	INSERT INTO peers (peer_id, last_time_check, active, score, consecutive_failures, avg_latency_ms, next_check_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(peer_id) DO UPDATE SET
+		last_time_check = excluded.last_time_check,
+		active = excluded.active,
+		score = excluded.score,
+		consecutive_failures = excluded.consecutive_failures,
+		avg_latency_ms = excluded.avg_latency_ms,
+		next_check_at = excluded.next_check_at
+`
+
+// sqliteStore is the original SQLite-backed Store. Every call is serialized
+// behind a single mutex, since the mattn/go-sqlite3 driver doesn't support
+// concurrent writers against one file.
+type sqliteStore struct {
+	db  *sql.DB
+	mu  sync.Mutex
+	hub *replicationHub
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &sqliteStore{db: db, hub: newReplicationHub()}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Println("\x1b[1;34m[INFO]\x1b[0m Database table 'peers' ensured.")
+	return s, nil
+}
+
+func (s *sqliteStore) migrate() error {
+	sqlStmt := `CREATE TABLE IF NOT EXISTS peers (
+		peer_id TEXT PRIMARY KEY,
+		last_time_check TIMESTAMP,
+		active BOOLEAN
+	);`
+	if _, err := s.db.Exec(sqlStmt); err != nil {
+		return fmt.Errorf("create table: %w", err)
+	}
+
+	existing := map[string]bool{}
+	rows, err := s.db.Query(`PRAGMA table_info(peers)`)
+	if err != nil {
+		return fmt.Errorf("inspect schema: %w", err)
+	}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, ctype string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan schema column: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	migrations := []struct{ column, ddl string }{
+		{"score", "ALTER TABLE peers ADD COLUMN score REAL DEFAULT 0"},
+		{"consecutive_failures", "ALTER TABLE peers ADD COLUMN consecutive_failures INT DEFAULT 0"},
+		{"avg_latency_ms", "ALTER TABLE peers ADD COLUMN avg_latency_ms REAL DEFAULT 0"},
+		{"next_check_at", "ALTER TABLE peers ADD COLUMN next_check_at TIMESTAMP"},
+	}
+	for _, m := range migrations {
+		if existing[m.column] {
+			continue
+		}
+		if _, err := s.db.Exec(m.ddl); err != nil {
+			return fmt.Errorf("add column %s: %w", m.column, err)
+		}
+		log.Printf("\x1b[1;34m[INFO]\x1b[0m Migrated peers table: added column %s.", m.column)
+	}
+
+	// Peers that predate next_check_at would otherwise sit at NULL forever
+	// and never get picked up by dueQuerySQLite.
+	if _, err := s.db.Exec(`UPDATE peers SET next_check_at = last_time_check WHERE next_check_at IS NULL`); err != nil {
+		return fmt.Errorf("backfill next_check_at: %w", err)
+	}
+
+	// dueQuerySQLite scans next_check_at and CountActive scans active on every
+	// probe cycle; without these the concurrent worker pool serializes on
+	// full table scans as the peers table grows.
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_peers_next_check_at ON peers(next_check_at)`); err != nil {
+		return fmt.Errorf("create next_check_at index: %w", err)
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_peers_active ON peers(active)`); err != nil {
+		return fmt.Errorf("create active index: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteStore) GetDuePeers(ctx context.Context, limit int) ([]string, error) {
+	s.mu.Lock()
+	rows, err := s.db.QueryContext(ctx, dueQuerySQLite, time.Now(), limit)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pids []string
+	for rows.Next() {
+		var pid string
+		if err := rows.Scan(&pid); err != nil {
+			return nil, err
+		}
+		pids = append(pids, pid)
+	}
+	return pids, rows.Err()
+}
+
+func (s *sqliteStore) Upsert(ctx context.Context, peerID string, checkTime time.Time, ok bool, latencyMs float64) (Peer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var prevScore, prevAvgLatency float64
+	var prevFailures int
+	var prevActive bool
+	err := s.db.QueryRowContext(ctx, `SELECT score, consecutive_failures, avg_latency_ms, active FROM peers WHERE peer_id = ?`, peerID).
+		Scan(&prevScore, &prevFailures, &prevAvgLatency, &prevActive)
+	if err != nil {
+		// New peer: start from a neutral score with no history.
+		prevScore, prevFailures, prevAvgLatency, prevActive = 0, 0, 0, false
+	}
+
+	p := scorePing(peerID, checkTime, ok, latencyMs, prevScore, prevFailures, prevAvgLatency)
+
+	_, err = s.db.ExecContext(ctx, upsertSQLSqlite, peerID, checkTime, ok, p.Score, p.ConsecutiveFailures, p.AvgLatencyMs, p.NextCheckAt)
+	if err != nil {
+		return Peer{}, err
+	}
+
+	recordActiveTransition(prevActive, p.Active)
+	s.hub.broadcast(p)
+	return p, nil
+}
+
+func (s *sqliteStore) MergeReplicated(ctx context.Context, p Peer) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing string
+	var prevActive bool
+	err := s.db.QueryRowContext(ctx, `SELECT last_time_check, active FROM peers WHERE peer_id = ?`, p.PeerID).Scan(&existing, &prevActive)
+	if err == nil {
+		if existingTime, perr := time.Parse(time.RFC3339Nano, existing); perr == nil && !p.LastTimeCheck.After(existingTime) {
+			return false, nil
+		}
+	} else {
+		prevActive = false
+	}
+
+	if _, err := s.db.ExecContext(ctx, upsertSQLSqlite, p.PeerID, p.LastTimeCheck, p.Active, p.Score, p.ConsecutiveFailures, p.AvgLatencyMs, p.NextCheckAt); err != nil {
+		return false, err
+	}
+
+	recordActiveTransition(prevActive, p.Active)
+	return true, nil
+}
+
+func (s *sqliteStore) ListPeers(ctx context.Context) ([]Peer, error) {
+	s.mu.Lock()
+	rows, err := s.db.QueryContext(ctx, `SELECT peer_id, last_time_check, active, score, consecutive_failures, avg_latency_ms, next_check_at FROM peers ORDER BY last_time_check DESC`)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var peers []Peer
+	for rows.Next() {
+		var p Peer
+		var ts string
+		var nextCheckTs sql.NullString
+		if err := rows.Scan(&p.PeerID, &ts, &p.Active, &p.Score, &p.ConsecutiveFailures, &p.AvgLatencyMs, &nextCheckTs); err != nil {
+			return nil, err
+		}
+		if p.LastTimeCheck, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			p.LastTimeCheck = time.Time{}
+		}
+		if nextCheckTs.Valid {
+			if p.NextCheckAt, err = time.Parse(time.RFC3339Nano, nextCheckTs.String); err != nil {
+				p.NextCheckAt = time.Time{}
+			}
+		}
+		peers = append(peers, p)
+	}
+	return peers, rows.Err()
+}
+
+func (s *sqliteStore) CountActive(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	row := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM peers WHERE active = 1`)
+	s.mu.Unlock()
+
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *sqliteStore) Subscribe() (<-chan Peer, func()) {
+	return s.hub.subscribe()
+}
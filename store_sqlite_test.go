@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSqliteStoreMergeReplicatedDropsStaleRow(t *testing.T) {
+	s, err := newSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	newer := time.Now()
+	older := newer.Add(-time.Hour)
+
+	if _, err := s.Upsert(ctx, "peer-stale", newer, true, 10); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	applied, err := s.MergeReplicated(ctx, Peer{PeerID: "peer-stale", LastTimeCheck: older, Active: false})
+	if err != nil {
+		t.Fatalf("MergeReplicated: %v", err)
+	}
+	if applied {
+		t.Error("MergeReplicated applied = true, want false for a row older than what's stored")
+	}
+
+	peers, err := s.ListPeers(ctx)
+	if err != nil {
+		t.Fatalf("ListPeers: %v", err)
+	}
+	if len(peers) != 1 || !peers[0].Active {
+		t.Errorf("ListPeers = %+v, want the original active row left untouched", peers)
+	}
+}
+
+func TestSqliteStoreMergeReplicatedAppliesNewerRow(t *testing.T) {
+	s, err := newSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	if _, err := s.Upsert(ctx, "peer-fresh", older, false, 0); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	applied, err := s.MergeReplicated(ctx, Peer{PeerID: "peer-fresh", LastTimeCheck: newer, Active: true, Score: 0.9})
+	if err != nil {
+		t.Fatalf("MergeReplicated: %v", err)
+	}
+	if !applied {
+		t.Error("MergeReplicated applied = false, want true for a row newer than what's stored")
+	}
+
+	peers, err := s.ListPeers(ctx)
+	if err != nil {
+		t.Fatalf("ListPeers: %v", err)
+	}
+	if len(peers) != 1 || !peers[0].Active || peers[0].Score != 0.9 {
+		t.Errorf("ListPeers = %+v, want the replicated row applied", peers)
+	}
+}
+
+func TestSqliteStoreGetDuePeersFiltersFuture(t *testing.T) {
+	s, err := newSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if _, err := s.Upsert(ctx, "peer-not-due", time.Now(), true, 1); err != nil {
+		t.Fatalf("Upsert(peer-not-due): %v", err)
+	}
+	if _, err := s.Upsert(ctx, "peer-due", time.Now(), true, 1); err != nil {
+		t.Fatalf("Upsert(peer-due): %v", err)
+	}
+
+	// Upsert always schedules next_check_at in the future; force peer-due's
+	// into the past directly so GetDuePeers' next_check_at <= now() filter is
+	// the only thing standing between it and peer-not-due's still-future row.
+	if _, err := s.db.ExecContext(ctx, `UPDATE peers SET next_check_at = ? WHERE peer_id = ?`, time.Now().Add(-time.Minute), "peer-due"); err != nil {
+		t.Fatalf("backdating next_check_at: %v", err)
+	}
+
+	due, err := s.GetDuePeers(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetDuePeers: %v", err)
+	}
+	if len(due) != 1 || due[0] != "peer-due" {
+		t.Errorf("GetDuePeers = %v, want only [peer-due]", due)
+	}
+}